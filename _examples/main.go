@@ -60,7 +60,7 @@ func createExampleData() (string, string) {
 		os.Exit(1)
 	}
 
-	if err := os.Symlink(filepath.Join(subDirectory, "my_file.txt"), filepath.Join(subDirectory, "my_link")); err != nil {
+	if err := os.Symlink("my_file.txt", filepath.Join(subDirectory, "my_link")); err != nil {
 		fmt.Println("create symlink error")
 		panic(err)
 		os.Exit(1)
@@ -0,0 +1,222 @@
+package targz
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// whiteoutPrefix marks a tar entry as recording the deletion of the sibling
+// path with the prefix stripped, the convention container image layers use
+// (e.g. OverlayFS, Docker's archive package) to represent removed files in a
+// layer that can otherwise only add entries.
+const whiteoutPrefix = ".wh."
+
+// Diff computes the changes from oldDir to newDir and returns them as a
+// gzip-compressed tar stream: entries added or modified in newDir are
+// written in full, and entries present in oldDir but missing from newDir are
+// recorded as zero-length whiteout entries. The returned ReadCloser streams
+// the diff as it's computed and must be closed by the caller once done
+// reading from it.
+func Diff(oldDir, newDir string) (io.ReadCloser, error) {
+	oldDir, err := filepath.Abs(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newDir, err = filepath.Abs(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(writeDiff(pw, oldDir, newDir))
+	}()
+
+	return pr, nil
+}
+
+// writeDiff does the work behind Diff, writing onto w instead of returning a
+// stream, so Diff can run it in a goroutine feeding an io.Pipe.
+func writeDiff(w io.Writer, oldDir, newDir string) (err error) {
+	tarWriter, err := NewWriter(w, Options{Compression: Gzip})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := tarWriter.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	hardlinks := map[hardlinkKey]string{}
+
+	// Whiteouts are written before the additions/modifications below, so
+	// that a path that changed type between oldDir and newDir (e.g. a
+	// directory replaced by a plain file) whiteouts the old entry before
+	// its replacement is written - the other order would let the whiteout
+	// delete the replacement on ApplyDiff instead of the stale original.
+	err = filepath.Walk(oldDir, func(currentPath string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+
+		name := strings.TrimPrefix(currentPath[len(oldDir):], string(filepath.Separator))
+		if name == "" {
+			return nil
+		}
+
+		newInfo, err := os.Lstat(filepath.Join(newDir, name))
+		switch {
+		case err == nil:
+			if newInfo.IsDir() == info.IsDir() {
+				// Still present, and not a directory<->non-directory type
+				// change - nothing to whiteout.
+				return nil
+			}
+		case isNotExistOrNotDir(err):
+			// Gone entirely (or, defensively, unreachable through some
+			// ancestor that's no longer a directory) - either way name
+			// itself needs a whiteout.
+		default:
+			return err
+		}
+
+		whiteout := filepath.Join(filepath.Dir(name), whiteoutPrefix+filepath.Base(name))
+		if err := tarWriter.WriteHeader(&tar.Header{Name: whiteout, Typeflag: tar.TypeReg, Mode: 0644}); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			// Everything beneath a deleted (or type-changed) directory is
+			// gone too; one whiteout for the directory itself covers the
+			// whole subtree.
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(newDir, func(currentPath string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+
+		name := strings.TrimPrefix(currentPath[len(newDir):], string(filepath.Separator))
+		if name == "" {
+			return nil
+		}
+
+		changed, err := entryChanged(filepath.Join(oldDir, name), info)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return nil
+		}
+
+		return writeEntry(tarWriter.Writer, currentPath, newDir, info, hardlinks, Options{})
+	})
+}
+
+// entryChanged reports whether newInfo describes a file added or modified
+// relative to whatever (if anything) exists at oldPath. It compares mode,
+// size and modification time rather than hashing contents, the same
+// metadata Docker's archive package uses to detect changed layer entries.
+func entryChanged(oldPath string, newInfo os.FileInfo) (bool, error) {
+	oldInfo, err := os.Lstat(oldPath)
+	if err != nil {
+		if isNotExistOrNotDir(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if oldInfo.Mode() != newInfo.Mode() {
+		return true, nil
+	}
+	if oldInfo.IsDir() {
+		return false, nil
+	}
+
+	return oldInfo.Size() != newInfo.Size() || !oldInfo.ModTime().Equal(newInfo.ModTime()), nil
+}
+
+// isNotExistOrNotDir reports whether err is either the usual "no such file"
+// error, or ENOTDIR - which Lstat also returns when a path's ancestor used
+// to be a directory but (on the other side of the diff) no longer is. Both
+// mean there's nothing of the expected kind at that path.
+func isNotExistOrNotDir(err error) bool {
+	return os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR)
+}
+
+// ApplyDiff extracts the tar stream produced by Diff (or any other
+// whiteout-encoded layer) onto dst: added/modified entries are (re)written,
+// and a whiteout entry removes the file or directory tree it names.
+func ApplyDiff(dst string, layer io.Reader) error {
+	dst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+
+	tarReader, err := NewReader(layer, Options{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		handleErrorInDefer(tarReader.Close())
+	}()
+
+	var opts Options
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		dir, base := filepath.Split(header.Name)
+		if strings.HasPrefix(base, whiteoutPrefix) {
+			target, err := validateExtractPath(dst, filepath.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)), opts.AllowAbsolutePaths, opts.AllowSymlinkEscape)
+			if err != nil {
+				return err
+			}
+
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		filename, err := validateExtractPath(dst, header.Name, opts.AllowAbsolutePaths, opts.AllowSymlinkEscape)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+
+		if err := extractEntry(tarReader, header, dst, filename, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
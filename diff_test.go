@@ -0,0 +1,121 @@
+package targz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_DiffAndApplyDiff(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "targz-diff")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldDir := filepath.Join(tmpDir, "old")
+	newDir := filepath.Join(tmpDir, "new")
+
+	mustWriteFile(t, filepath.Join(oldDir, "unchanged.txt"), "same")
+	mustWriteFile(t, filepath.Join(oldDir, "removed.txt"), "bye")
+	mustWriteFile(t, filepath.Join(oldDir, "modified.txt"), "before")
+
+	mustWriteFile(t, filepath.Join(newDir, "unchanged.txt"), "same")
+	mustWriteFile(t, filepath.Join(newDir, "modified.txt"), "after, and longer")
+	mustWriteFile(t, filepath.Join(newDir, "added.txt"), "hi")
+
+	// entryChanged treats a differing mtime as a change even if the content
+	// is identical, so give the unmodified file matching mtimes on both sides.
+	sameTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(oldDir, "unchanged.txt"), sameTime, sameTime); err != nil {
+		t.Fatalf("Chtimes error: %s", err)
+	}
+	if err := os.Chtimes(filepath.Join(newDir, "unchanged.txt"), sameTime, sameTime); err != nil {
+		t.Fatalf("Chtimes error: %s", err)
+	}
+
+	layer, err := Diff(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("Diff error: %s", err)
+	}
+	defer layer.Close()
+
+	dst := filepath.Join(tmpDir, "dst")
+	mustWriteFile(t, filepath.Join(dst, "unchanged.txt"), "same")
+	mustWriteFile(t, filepath.Join(dst, "removed.txt"), "bye")
+	mustWriteFile(t, filepath.Join(dst, "modified.txt"), "before")
+
+	if err := ApplyDiff(dst, layer); err != nil {
+		t.Fatalf("ApplyDiff error: %s", err)
+	}
+
+	assertFileContent(t, filepath.Join(dst, "unchanged.txt"), "same")
+	assertFileContent(t, filepath.Join(dst, "modified.txt"), "after, and longer")
+	assertFileContent(t, filepath.Join(dst, "added.txt"), "hi")
+
+	if _, err := os.Stat(filepath.Join(dst, "removed.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected removed.txt to be deleted by the diff, stat error: %v", err)
+	}
+}
+
+func Test_DiffAndApplyDiffTypeChange(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "targz-diff-typechange")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldDir := filepath.Join(tmpDir, "old")
+	newDir := filepath.Join(tmpDir, "new")
+
+	// "dirToFile" is a directory with a child in oldDir but a plain file in
+	// newDir; "fileToDir" is the opposite. Both must round-trip through
+	// Diff/ApplyDiff without the raw ENOTDIR crash this used to trigger.
+	mustWriteFile(t, filepath.Join(oldDir, "dirToFile", "child.txt"), "gone")
+	mustWriteFile(t, filepath.Join(oldDir, "fileToDir"), "gone")
+
+	mustWriteFile(t, filepath.Join(newDir, "dirToFile"), "now a file")
+	mustWriteFile(t, filepath.Join(newDir, "fileToDir", "child.txt"), "now a dir")
+
+	layer, err := Diff(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("Diff error: %s", err)
+	}
+	defer layer.Close()
+
+	dst := filepath.Join(tmpDir, "dst")
+	mustWriteFile(t, filepath.Join(dst, "dirToFile", "child.txt"), "gone")
+	mustWriteFile(t, filepath.Join(dst, "fileToDir"), "gone")
+
+	if err := ApplyDiff(dst, layer); err != nil {
+		t.Fatalf("ApplyDiff error: %s", err)
+	}
+
+	assertFileContent(t, filepath.Join(dst, "dirToFile"), "now a file")
+	assertFileContent(t, filepath.Join(dst, "fileToDir", "child.txt"), "now a dir")
+}
+
+func mustWriteFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll error: %s", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile error: %s", err)
+	}
+}
+
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile error: %s", err)
+	}
+	if string(got) != want {
+		t.Errorf("expected %q to contain %q, got %q", path, want, string(got))
+	}
+}
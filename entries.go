@@ -0,0 +1,80 @@
+package targz
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// extractSymlink creates filename as a symlink pointing at header.Linkname,
+// after checking that the resolved target doesn't escape directory.
+func extractSymlink(directory, filename string, header *tar.Header, opts Options) error {
+	if _, err := validateLinkTarget(directory, filepath.Dir(header.Name), header.Linkname, opts.AllowAbsolutePaths, opts.AllowSymlinkEscape); err != nil {
+		return err
+	}
+
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.Symlink(header.Linkname, filename)
+}
+
+// extractHardlink creates filename as a hardlink to the entry header.Linkname
+// refers to, after checking that its resolved path doesn't escape directory.
+// Unlike a symlink target, a hardlink's Linkname is an archive-root-relative
+// path to another entry, not a path relative to its own directory.
+func extractHardlink(directory, filename string, header *tar.Header, opts Options) error {
+	target, err := validateExtractPath(directory, header.Linkname, opts.AllowAbsolutePaths, opts.AllowSymlinkEscape)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.Link(target, filename)
+}
+
+// extractRegularFile writes the contents of the current tar entry to filename.
+func extractRegularFile(tarReader *Reader, filename string, header *tar.Header) error {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, header.FileInfo().Mode())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, tarReader); err != nil {
+		handleErrorInDefer(file.Close())
+		return err
+	}
+
+	return file.Close()
+}
+
+// restoreMetadata applies the mode and modification time header carries to
+// path, and - unless opts.NoLchown is set - its uid/gid via os.Lchown.
+// Permission errors from Lchown are swallowed, since running unprivileged
+// (the common case outside of container image builds) can't set ownership.
+func restoreMetadata(path string, header *tar.Header, opts Options) error {
+	if header.Typeflag != tar.TypeSymlink {
+		if err := os.Chmod(path, header.FileInfo().Mode()); err != nil {
+			return err
+		}
+
+		if err := os.Chtimes(path, header.ModTime, header.ModTime); err != nil {
+			return err
+		}
+	}
+
+	if opts.NoLchown {
+		return nil
+	}
+
+	if err := os.Lchown(path, header.Uid, header.Gid); err != nil && !os.IsPermission(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build linux
+
+package targz
+
+import (
+	"archive/tar"
+	"fmt"
+	"syscall"
+)
+
+// extractDevice creates filename as the character or block device header
+// describes, using Linux's major/minor packing for syscall.Mknod's dev
+// argument (other unix platforms pack Rdev differently, so this is kept
+// Linux-specific rather than applied under the broader !windows build tag).
+func extractDevice(filename string, header *tar.Header, opts Options) error {
+	mode := uint32(header.FileInfo().Mode().Perm())
+	if header.Typeflag == tar.TypeChar {
+		mode |= syscall.S_IFCHR
+	} else {
+		mode |= syscall.S_IFBLK
+	}
+
+	dev := mkdev(uint32(header.Devmajor), uint32(header.Devminor))
+	if err := syscall.Mknod(filename, mode, dev); err != nil {
+		return fmt.Errorf("targz: creating device %q: %w", filename, err)
+	}
+
+	return nil
+}
+
+// mkdev packs a major/minor device number pair using Linux's encoding,
+// matching how archive/tar's FileInfoHeader unpacks Rdev on linux.
+func mkdev(major, minor uint32) int {
+	dev := (uint64(major) & 0x00000fff) << 8
+	dev |= (uint64(major) & 0xfffff000) << 32
+	dev |= (uint64(minor) & 0x000000ff) << 0
+	dev |= (uint64(minor) & 0xffffff00) << 12
+	return int(dev)
+}
@@ -0,0 +1,16 @@
+//go:build !windows && !linux
+
+package targz
+
+import (
+	"archive/tar"
+	"fmt"
+)
+
+// extractDevice always fails on non-Linux unix platforms: the major/minor
+// packing syscall.Mknod needs is OS-specific, and only the Linux encoding is
+// implemented here (see entries_linux.go), so guessing would silently create
+// a device with the wrong major/minor instead.
+func extractDevice(filename string, header *tar.Header, opts Options) error {
+	return fmt.Errorf("targz: extracting device file %q is not supported on this platform", filename)
+}
@@ -0,0 +1,70 @@
+package targz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_CompressAndExtractPreservesSymlinksAndHardlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks/hardlinks are not exercised on windows")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "targz-entrytypes")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	directory := filepath.Join(tmpDir, "my_folder")
+	subDirectory := filepath.Join(directory, "my_sub_folder")
+	if err := os.MkdirAll(subDirectory, 0755); err != nil {
+		t.Fatalf("MkdirAll error: %s", err)
+	}
+
+	realFile := filepath.Join(subDirectory, "real.txt")
+	if err := os.WriteFile(realFile, []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile error: %s", err)
+	}
+	if err := os.Symlink("real.txt", filepath.Join(subDirectory, "link.txt")); err != nil {
+		t.Fatalf("Symlink error: %s", err)
+	}
+	if err := os.Link(realFile, filepath.Join(subDirectory, "hardlink.txt")); err != nil {
+		t.Fatalf("Link error: %s", err)
+	}
+
+	archivePath := filepath.Join(tmpDir, "my_archive.tar.gz")
+	if err := Compress(directory, archivePath); err != nil {
+		t.Fatalf("Compress error: %s", err)
+	}
+
+	extractedDir := filepath.Join(tmpDir, "extracted")
+	if err := Extract(archivePath, extractedDir); err != nil {
+		t.Fatalf("Extract error: %s", err)
+	}
+
+	extractedRoot := filepath.Join(extractedDir, "my_folder", "my_sub_folder")
+
+	target, err := os.Readlink(filepath.Join(extractedRoot, "link.txt"))
+	if err != nil {
+		t.Fatalf("Readlink error: %s", err)
+	}
+	if target != "real.txt" {
+		t.Errorf("expected symlink target %q, got %q", "real.txt", target)
+	}
+
+	realInfo, err := os.Stat(filepath.Join(extractedRoot, "real.txt"))
+	if err != nil {
+		t.Fatalf("Stat error: %s", err)
+	}
+	hardlinkInfo, err := os.Stat(filepath.Join(extractedRoot, "hardlink.txt"))
+	if err != nil {
+		t.Fatalf("Stat error: %s", err)
+	}
+	if !os.SameFile(realInfo, hardlinkInfo) {
+		t.Errorf("expected hardlink.txt to be the same file as real.txt after extraction")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package targz
+
+import (
+	"archive/tar"
+	"fmt"
+)
+
+// extractDevice always fails on Windows: it has no equivalent of Unix's
+// mknod, so character and block device entries can't be created here.
+func extractDevice(filename string, header *tar.Header, opts Options) error {
+	return fmt.Errorf("targz: extracting device file %q is not supported on windows", filename)
+}
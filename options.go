@@ -0,0 +1,64 @@
+package targz
+
+import "archive/tar"
+
+// Options configures a Writer, Reader, or the Compress/Extract family of
+// functions.
+type Options struct {
+	// Compression selects the codec used for the archive stream by NewWriter;
+	// its zero value is Uncompressed. NewReader ignores it and always
+	// autodetects the codec from the stream instead.
+	Compression Compression
+
+	// AllowAbsolutePaths makes Extract/ExtractWithOptions honor archive
+	// entries (and symlink/hardlink targets) with an absolute Name or
+	// Linkname instead of rejecting them. Defaults to false: absolute paths
+	// are refused, since they usually indicate a maliciously crafted archive.
+	AllowAbsolutePaths bool
+
+	// AllowSymlinkEscape makes Extract/ExtractWithOptions honor archive
+	// entries (and symlink/hardlink targets) that resolve outside the
+	// destination directory instead of rejecting them. Defaults to false:
+	// such entries are refused, closing the "Zip Slip" path-traversal class
+	// of bug.
+	AllowSymlinkEscape bool
+
+	// NoLchown skips restoring the uid/gid an archive entry carries via
+	// os.Lchown during extraction. Defaults to false (ownership is restored
+	// on a best-effort basis; permission errors, expected when extracting
+	// unprivileged, are ignored).
+	NoLchown bool
+
+	// Includes lists additional sibling paths that CompressWithOptions
+	// archives alongside its inputFilePath. Relative entries are resolved
+	// against the current working directory, like inputFilePath itself.
+	// CompressWithOptions errors if an entry isn't actually a sibling of
+	// inputFilePath (in the same parent directory). It has no effect on
+	// Extract.
+	Includes []string
+
+	// ExcludePatterns lists patterns matched with filepath.Match against
+	// each entry's archive-relative path (a trailing "/**" also matches
+	// everything below it, e.g. "node_modules/**"). Matching entries, and
+	// for directories their whole subtree, are left out of the archive. It
+	// has no effect on Extract.
+	ExcludePatterns []string
+
+	// Filter, when set, is called for every entry CompressWithOptions is
+	// about to add. Returning skip == true leaves the entry (and, for a
+	// directory, its subtree) out of the archive. Filter may also mutate
+	// header in place, e.g. to normalize permissions, squash uid/gid, or
+	// clamp mtime for reproducible builds. It has no effect on Extract.
+	Filter func(header *tar.Header, path string) (skip bool, err error)
+
+	// Concurrency, when greater than zero, makes a Gzip Writer compress the
+	// tar stream in parallel: it is split into BlockSize chunks, each
+	// gzipped by one of Concurrency workers, and the resulting members are
+	// written out in order. Left at zero (the default), gzip.Writer compresses
+	// serially as before. Has no effect on other compression codecs.
+	Concurrency int
+
+	// BlockSize sets the chunk size parallel gzip compression splits the tar
+	// stream into when Concurrency is set. Defaults to 128 KiB.
+	BlockSize int
+}
@@ -0,0 +1,193 @@
+package targz
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_CompressWithOptionsExcludePatterns(t *testing.T) {
+	tmpDir, dirToCompress := createTestData()
+	defer os.RemoveAll(tmpDir)
+
+	createFiles(dirToCompress, "keep.txt")
+	if err := os.MkdirAll(filepath.Join(dirToCompress, "node_modules", "pkg"), 0755); err != nil {
+		t.Fatalf("MkdirAll error: %s", err)
+	}
+	createFiles(filepath.Join(dirToCompress, "node_modules", "pkg"), "index.js")
+
+	archivePath := filepath.Join(tmpDir, "my_archive.tar.gz")
+	opts := Options{ExcludePatterns: []string{"my_folder/node_modules/**"}}
+	if err := CompressWithOptions(dirToCompress, archivePath, opts); err != nil {
+		t.Fatalf("CompressWithOptions error: %s", err)
+	}
+
+	names := archiveEntryNames(t, archivePath)
+	for _, name := range names {
+		if name == "my_folder/node_modules" || name == "my_folder/node_modules/pkg/index.js" {
+			t.Errorf("expected %q to be excluded, found it in archive entries %v", name, names)
+		}
+	}
+}
+
+func Test_CompressWithOptionsFilter(t *testing.T) {
+	tmpDir, dirToCompress := createTestData()
+	defer os.RemoveAll(tmpDir)
+
+	createFiles(dirToCompress, "secret.key", "keep.txt")
+
+	archivePath := filepath.Join(tmpDir, "my_archive.tar.gz")
+	opts := Options{
+		Filter: func(header *tar.Header, path string) (bool, error) {
+			return filepath.Base(header.Name) == "secret.key", nil
+		},
+	}
+	if err := CompressWithOptions(dirToCompress, archivePath, opts); err != nil {
+		t.Fatalf("CompressWithOptions error: %s", err)
+	}
+
+	for _, name := range archiveEntryNames(t, archivePath) {
+		if filepath.Base(name) == "secret.key" {
+			t.Errorf("expected secret.key to be filtered out of the archive")
+		}
+	}
+}
+
+func Test_CompressWithOptionsIncludes(t *testing.T) {
+	tmpDir, dirToCompress := createTestData()
+	defer os.RemoveAll(tmpDir)
+
+	createFiles(dirToCompress, "keep.txt")
+
+	siblingDir := filepath.Join(tmpDir, "my_other_folder")
+	if err := os.MkdirAll(siblingDir, 0755); err != nil {
+		t.Fatalf("MkdirAll error: %s", err)
+	}
+	createFiles(siblingDir, "other.txt")
+
+	archivePath := filepath.Join(tmpDir, "my_archive.tar.gz")
+	opts := Options{Includes: []string{siblingDir}}
+	if err := CompressWithOptions(dirToCompress, archivePath, opts); err != nil {
+		t.Fatalf("CompressWithOptions error: %s", err)
+	}
+
+	names := archiveEntryNames(t, archivePath)
+	for _, want := range []string{"my_folder/keep.txt", "my_other_folder/other.txt"} {
+		found := false
+		for _, name := range names {
+			if name == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected entry %q in archive entries %v", want, names)
+		}
+	}
+}
+
+func Test_CompressWithOptionsIncludesRejectsNonSibling(t *testing.T) {
+	tmpDir, dirToCompress := createTestData()
+	defer os.RemoveAll(tmpDir)
+
+	nonSibling, err := ioutil.TempDir("", "targz-not-a-sibling")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err)
+	}
+	defer os.RemoveAll(nonSibling)
+	createFiles(nonSibling, "other.txt")
+
+	archivePath := filepath.Join(tmpDir, "my_archive.tar.gz")
+	opts := Options{Includes: []string{nonSibling}}
+	if err := CompressWithOptions(dirToCompress, archivePath, opts); err == nil {
+		t.Errorf("expected CompressWithOptions to reject a non-sibling include")
+	}
+}
+
+func Test_CompressWithOptionsExcludePatternsSkipsFirstHardlinkCopy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hardlinks are not exercised on windows")
+	}
+
+	tmpDir, dirToCompress := createTestData()
+	defer os.RemoveAll(tmpDir)
+
+	// filepath.Walk visits names lexically, so "hardlink.txt" is walked -
+	// and, pre-fix, wrongly recorded as the hardlink's archive target -
+	// before "real.txt", even though it's the one being excluded here.
+	realFile := filepath.Join(dirToCompress, "real.txt")
+	if err := os.WriteFile(realFile, []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile error: %s", err)
+	}
+	if err := os.Link(realFile, filepath.Join(dirToCompress, "hardlink.txt")); err != nil {
+		t.Fatalf("Link error: %s", err)
+	}
+
+	archivePath := filepath.Join(tmpDir, "my_archive.tar.gz")
+	opts := Options{ExcludePatterns: []string{"my_folder/hardlink.txt"}}
+	if err := CompressWithOptions(dirToCompress, archivePath, opts); err != nil {
+		t.Fatalf("CompressWithOptions error: %s", err)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer file.Close()
+
+	reader, err := NewReader(file, Options{})
+	if err != nil {
+		t.Fatalf("NewReader error: %s", err)
+	}
+	defer reader.Close()
+
+	var sawRealFile bool
+	for {
+		header, err := reader.Next()
+		if err != nil {
+			break
+		}
+		if header.Typeflag == tar.TypeLink && header.Linkname == "my_folder/hardlink.txt" {
+			t.Errorf("entry must not reference excluded entry %q", header.Linkname)
+		}
+		if header.Name == "my_folder/real.txt" {
+			sawRealFile = true
+			if header.Typeflag == tar.TypeLink {
+				t.Errorf("real.txt should be written in full, not as a TypeLink")
+			}
+		}
+	}
+	if !sawRealFile {
+		t.Errorf("expected my_folder/real.txt in the archive")
+	}
+}
+
+func archiveEntryNames(t *testing.T, archivePath string) []string {
+	t.Helper()
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer file.Close()
+
+	reader, err := NewReader(file, Options{})
+	if err != nil {
+		t.Fatalf("NewReader error: %s", err)
+	}
+	defer reader.Close()
+
+	var names []string
+	for {
+		header, err := reader.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,160 @@
+package targz
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// defaultBlockSize is the chunk size parallelGzipWriter compresses per
+// worker when Options.BlockSize is left at its zero value.
+const defaultBlockSize = 128 * 1024
+
+// blockResult carries one block's compressed bytes (or the error that
+// prevented producing them) from a worker back to parallelGzipWriter.drain.
+type blockResult struct {
+	data []byte
+	err  error
+}
+
+// parallelGzipWriter is a pigz-style concurrent gzip writer: the incoming
+// stream is split into BlockSize chunks, each compressed independently on a
+// worker from a bounded pool, and the resulting gzip members are written to
+// the underlying writer in their original order. Concatenated gzip members
+// are themselves a valid gzip stream per RFC 1952, decompressing to the
+// concatenation of their contents, so the output reads back like any other
+// gzip file.
+type parallelGzipWriter struct {
+	w         io.Writer
+	blockSize int
+	buf       bytes.Buffer
+	sem       chan struct{}
+	wg        sync.WaitGroup
+	results   chan chan blockResult
+	done      chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func newParallelGzipWriter(w io.Writer, concurrency, blockSize int) *parallelGzipWriter {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	if blockSize <= 0 {
+		blockSize = defaultBlockSize
+	}
+
+	pgw := &parallelGzipWriter{
+		w:         w,
+		blockSize: blockSize,
+		sem:       make(chan struct{}, concurrency),
+		results:   make(chan chan blockResult, concurrency),
+		done:      make(chan struct{}),
+	}
+
+	go pgw.drain()
+
+	return pgw
+}
+
+// drain writes each block's compressed bytes to w in submission order: it
+// waits on each block's result channel in turn, so a block that finishes
+// compressing early still waits behind any still-running earlier block.
+func (pgw *parallelGzipWriter) drain() {
+	defer close(pgw.done)
+
+	for resultCh := range pgw.results {
+		result := <-resultCh
+		if pgw.setErr(result.err) != nil {
+			continue
+		}
+		if _, err := pgw.w.Write(result.data); err != nil {
+			pgw.setErr(err)
+		}
+	}
+}
+
+func (pgw *parallelGzipWriter) setErr(err error) error {
+	if err == nil {
+		pgw.mu.Lock()
+		defer pgw.mu.Unlock()
+		return pgw.err
+	}
+
+	pgw.mu.Lock()
+	defer pgw.mu.Unlock()
+	if pgw.err == nil {
+		pgw.err = err
+	}
+	return pgw.err
+}
+
+// Write buffers p, flushing and dispatching a compression job for every
+// full BlockSize chunk it accumulates.
+func (pgw *parallelGzipWriter) Write(p []byte) (int, error) {
+	total := len(p)
+
+	for len(p) > 0 {
+		free := pgw.blockSize - pgw.buf.Len()
+		if free > len(p) {
+			free = len(p)
+		}
+		pgw.buf.Write(p[:free])
+		p = p[free:]
+
+		if pgw.buf.Len() == pgw.blockSize {
+			pgw.flushBlock()
+		}
+	}
+
+	return total, nil
+}
+
+func (pgw *parallelGzipWriter) flushBlock() {
+	block := make([]byte, pgw.buf.Len())
+	copy(block, pgw.buf.Bytes())
+	pgw.buf.Reset()
+
+	resultCh := make(chan blockResult, 1)
+	pgw.results <- resultCh
+
+	pgw.sem <- struct{}{}
+	pgw.wg.Add(1)
+	go func() {
+		defer pgw.wg.Done()
+		defer func() { <-pgw.sem }()
+		resultCh <- compressBlock(block)
+	}()
+}
+
+func compressBlock(block []byte) blockResult {
+	var buf bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&buf)
+	if _, err := gzipWriter.Write(block); err != nil {
+		return blockResult{err: err}
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return blockResult{err: err}
+	}
+
+	return blockResult{data: buf.Bytes()}
+}
+
+// Close flushes any buffered bytes as a final block, then waits for every
+// compression worker and the writer goroutine to finish before returning
+// the first error (if any) encountered along the way.
+func (pgw *parallelGzipWriter) Close() error {
+	if pgw.buf.Len() > 0 {
+		pgw.flushBlock()
+	}
+
+	close(pgw.results)
+	pgw.wg.Wait()
+	<-pgw.done
+
+	return pgw.setErr(nil)
+}
@@ -0,0 +1,57 @@
+package targz
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ParallelGzipWriterRoundTrip(t *testing.T) {
+	input := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog\n"), 10000)
+
+	var compressed bytes.Buffer
+	pgw := newParallelGzipWriter(&compressed, 4, 4096)
+
+	if _, err := pgw.Write(input); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	if err := pgw.Close(); err != nil {
+		t.Fatalf("Close error: %s", err)
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(compressed.Bytes()))
+	if err != nil {
+		t.Fatalf("gzip.NewReader error: %s", err)
+	}
+	defer gzipReader.Close()
+
+	got, err := io.ReadAll(gzipReader)
+	if err != nil {
+		t.Fatalf("ReadAll error: %s", err)
+	}
+
+	if !bytes.Equal(got, input) {
+		t.Errorf("decompressed output does not match input (got %d bytes, want %d)", len(got), len(input))
+	}
+}
+
+func Test_CompressWithOptionsConcurrency(t *testing.T) {
+	tmpDir, dirToCompress := createTestData()
+	defer os.RemoveAll(tmpDir)
+
+	createFiles(dirToCompress, "file1.txt", "file2.txt")
+
+	archivePath := filepath.Join(tmpDir, "my_archive.tar.gz")
+	opts := Options{Concurrency: 2, BlockSize: 4096}
+	if err := CompressWithOptions(dirToCompress, archivePath, opts); err != nil {
+		t.Fatalf("CompressWithOptions error: %s", err)
+	}
+
+	extractedDir := filepath.Join(tmpDir, "extracted")
+	if err := Extract(archivePath, extractedDir); err != nil {
+		t.Fatalf("Extract error: %s", err)
+	}
+}
@@ -0,0 +1,75 @@
+package targz
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// sniffLen is the number of bytes peeked from a stream to autodetect its
+// compression codec. It must be at least as long as the longest magic number
+// in DetectCompression (bzip2's, at 3 bytes).
+const sniffLen = 3
+
+// Reader untars and decompresses data read from it, one tar.Reader method
+// call at a time. It wraps an underlying io.Reader, autodetecting its
+// compression codec by sniffing magic bytes.
+type Reader struct {
+	*tar.Reader
+	decompressor io.Closer
+}
+
+// NewReader returns a Reader that decompresses and untars r. The compression
+// codec is autodetected from the stream; opts.Compression is ignored.
+func NewReader(r io.Reader, opts Options) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	compression := DetectCompression(magic)
+
+	decompressor, err := newDecompressor(br, compression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{Reader: tar.NewReader(decompressor), decompressor: decompressor}, nil
+}
+
+// Close releases resources held by the underlying compression codec.
+func (r *Reader) Close() error {
+	if r.decompressor == nil {
+		return nil
+	}
+
+	return r.decompressor.Close()
+}
+
+// decompressReader is an io.Reader whose Close releases codec resources, or
+// is a no-op for codecs (like bzip2) that don't hold any.
+type decompressReader interface {
+	io.Reader
+	io.Closer
+}
+
+func newDecompressor(r io.Reader, compression Compression) (decompressReader, error) {
+	switch compression {
+	case Uncompressed:
+		return nopReadCloser{r}, nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Bzip2:
+		return nopReadCloser{bzip2.NewReader(r)}, nil
+	default:
+		return nil, fmt.Errorf("targz: unknown compression %d", compression)
+	}
+}
+
+type nopReadCloser struct{ io.Reader }
+
+func (nopReadCloser) Close() error { return nil }
@@ -0,0 +1,124 @@
+package targz
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinkResolutions bounds the number of symlinks secureJoin follows
+// while resolving a single path, guarding against symlink loops.
+const maxSymlinkResolutions = 255
+
+// validateExtractPath resolves name (a tar header's Name or Linkname) against
+// directory and guards against path-traversal ("Zip Slip") archives: entries
+// that are absolute, or whose lexical path escapes directory, are rejected
+// unless allowAbsolute/allowEscape say otherwise. Resolution then walks the
+// real filesystem (not just the lexical path): a symlink an earlier entry (or
+// something already in directory) planted at an intermediate path component
+// is followed as if directory were the filesystem root, so the result stays
+// inside directory instead of breaking out (no error is returned for it),
+// mirroring Docker's FollowSymlinkInScope.
+func validateExtractPath(directory, name string, allowAbsolute, allowEscape bool) (string, error) {
+	if filepath.IsAbs(name) && !allowAbsolute {
+		return "", fmt.Errorf("targz: refusing to extract %q: absolute paths are not allowed", name)
+	}
+
+	directory = filepath.Clean(directory)
+
+	if allowEscape {
+		return filepath.Join(directory, name), nil
+	}
+
+	// Reject a literal ".." escape lexically, before symlinks ever enter
+	// into it - this also covers the case where directory itself doesn't
+	// exist yet, which secureJoin can't resolve against.
+	lexicalTarget := filepath.Join(directory, name)
+	if lexicalTarget != directory && !strings.HasPrefix(lexicalTarget, directory+string(filepath.Separator)) {
+		return "", fmt.Errorf("targz: refusing to extract %q: resolved path %q escapes destination %q", name, lexicalTarget, directory)
+	}
+
+	// Resolve any symlinks already on disk along the way, confined to
+	// directory, so a symlink planted at an intermediate path component
+	// can't be used to escape even though the lexical path above doesn't.
+	target, err := secureJoin(directory, name)
+	if err != nil {
+		return "", fmt.Errorf("targz: refusing to extract %q: %w", name, err)
+	}
+
+	return target, nil
+}
+
+// validateLinkTarget resolves a symlink/hardlink's target (header.Linkname)
+// relative to the directory its entry is being extracted into, and applies
+// the same escape checks as validateExtractPath.
+func validateLinkTarget(directory, entryDir, linkname string, allowAbsolute, allowEscape bool) (string, error) {
+	if filepath.IsAbs(linkname) {
+		return validateExtractPath(directory, linkname, allowAbsolute, allowEscape)
+	}
+
+	return validateExtractPath(directory, filepath.Join(entryDir, linkname), allowAbsolute, allowEscape)
+}
+
+// secureJoin resolves name against root the way the kernel would when
+// opening it, but treats root as if it were the filesystem root: any
+// symlink already on disk along the way - including one whose target is
+// absolute - is resolved relative to root instead of the real "/", so it
+// can never point the result outside of root. Path components that don't
+// exist yet are joined lexically, since there's nothing on disk to follow.
+func secureJoin(root, name string) (string, error) {
+	var path string // resolved so far, relative to root
+	var symlinksFollowed int
+
+	remaining := filepath.FromSlash(name)
+	for remaining != "" {
+		var part string
+		if i := strings.IndexRune(remaining, filepath.Separator); i == -1 {
+			part, remaining = remaining, ""
+		} else {
+			part, remaining = remaining[:i], remaining[i+1:]
+		}
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			path = filepath.Dir(path)
+			continue
+		}
+
+		candidate := filepath.Join(root, path, part)
+		info, err := os.Lstat(candidate)
+		if err != nil {
+			if os.IsNotExist(err) {
+				path = filepath.Join(path, part)
+				continue
+			}
+			return "", err
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			path = filepath.Join(path, part)
+			continue
+		}
+
+		symlinksFollowed++
+		if symlinksFollowed > maxSymlinkResolutions {
+			return "", errors.New("too many levels of symbolic links")
+		}
+
+		dest, err := os.Readlink(candidate)
+		if err != nil {
+			return "", err
+		}
+
+		if filepath.IsAbs(dest) {
+			path = ""
+		}
+		remaining = dest + string(filepath.Separator) + remaining
+	}
+
+	return filepath.Join(root, path), nil
+}
@@ -0,0 +1,117 @@
+package targz
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ExtractRejectsPathTraversal(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "targz-zipslip")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, "evil.tar.gz")
+	writeArchive(t, archivePath, []tar.Header{
+		{Name: "../../etc/passwd", Mode: 0644, Size: 0, Typeflag: tar.TypeReg},
+	}, nil)
+
+	outputDir := filepath.Join(tmpDir, "out")
+	err = Extract(archivePath, outputDir)
+	if err == nil {
+		t.Errorf("expected Extract to reject a path-traversal entry")
+	}
+}
+
+func Test_ExtractRejectsAbsolutePath(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "targz-abspath")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, "evil.tar.gz")
+	writeArchive(t, archivePath, []tar.Header{
+		{Name: "/etc/passwd", Mode: 0644, Size: 0, Typeflag: tar.TypeReg},
+	}, nil)
+
+	outputDir := filepath.Join(tmpDir, "out")
+	err = Extract(archivePath, outputDir)
+	if err == nil {
+		t.Errorf("expected Extract to reject an absolute-path entry")
+	}
+}
+
+func Test_ExtractRejectsSymlinkEscapeThroughExistingComponent(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "targz-symlink-escape")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	outsideDir := filepath.Join(tmpDir, "outside")
+	if err := os.MkdirAll(outsideDir, 0755); err != nil {
+		t.Fatalf("MkdirAll error: %s", err)
+	}
+
+	outputDir := filepath.Join(tmpDir, "out")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("MkdirAll error: %s", err)
+	}
+	if err := os.Symlink(outsideDir, filepath.Join(outputDir, "etc")); err != nil {
+		t.Fatalf("Symlink error: %s", err)
+	}
+
+	archivePath := filepath.Join(tmpDir, "evil.tar.gz")
+	writeArchive(t, archivePath, []tar.Header{
+		{Name: "etc/passwd", Mode: 0644, Size: 0, Typeflag: tar.TypeReg},
+	}, nil)
+
+	// The "etc" symlink is resolved, but confined to outputDir rather than
+	// followed out to outsideDir - it must not land there.
+	if err := Extract(archivePath, outputDir); err != nil {
+		t.Fatalf("Extract error: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(outsideDir, "passwd")); !os.IsNotExist(err) {
+		t.Errorf("expected %q not to be extracted through the planted symlink, got err=%v", filepath.Join(outsideDir, "passwd"), err)
+	}
+}
+
+// writeArchive tars headers (each followed by the matching contents, if
+// any; an entry without contents is given no data, so its Size must be 0)
+// and gzips the result to path.
+func writeArchive(t *testing.T, path string, headers []tar.Header, contents [][]byte) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create error: %s", err)
+	}
+	defer file.Close()
+
+	writer, err := NewWriter(file, Options{Compression: Gzip})
+	if err != nil {
+		t.Fatalf("NewWriter error: %s", err)
+	}
+
+	for i, header := range headers {
+		h := header
+		if err := writer.WriteHeader(&h); err != nil {
+			t.Fatalf("WriteHeader error: %s", err)
+		}
+		if i < len(contents) {
+			if _, err := writer.Write(contents[i]); err != nil {
+				t.Fatalf("Write error: %s", err)
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close error: %s", err)
+	}
+}
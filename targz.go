@@ -7,12 +7,14 @@
 //
 // This creates an archive in ./my_archive.tar.gz with the folder "compress" (last in the path).
 // And extracts the folder "compress" to "directory/to/extract/to/". The folder structure is created if it doesn't exist.
+//
+// Compress and Extract are thin wrappers around the streaming Writer and Reader types,
+// which operate on any io.Writer/io.Reader (a network socket, an HTTP body, os.Stdin/Stdout, ...)
+// instead of requiring a path on disk.
 package targz
 
 import (
 	"archive/tar"
-	"bufio"
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
@@ -27,7 +29,14 @@ import (
 // Only adds the last directory in inputFilePath to the archive, not the whole path.
 // It tries to create the directory structure outputFilePath contains if it doesn't exist.
 // It returns potential errors to be checked or nil if everything works.
-func Compress(inputFilePath, outputFilePath string) (err error) {
+func Compress(inputFilePath, outputFilePath string) error {
+	return CompressWithOptions(inputFilePath, outputFilePath, Options{})
+}
+
+// CompressWithOptions behaves like Compress. opts.Includes archives further
+// sibling paths into the same archive, and opts.ExcludePatterns/opts.Filter
+// leave matching entries out.
+func CompressWithOptions(inputFilePath, outputFilePath string, opts Options) (err error) {
 	inputFilePath = stripTrailingSlashes(inputFilePath)
 	inputFilePath, outputFilePath, err = makeAbsolute(inputFilePath, outputFilePath)
 	if err != nil {
@@ -56,7 +65,21 @@ func Compress(inputFilePath, outputFilePath string) (err error) {
 		subPath = filepath.Dir(inputFilePath)
 	}
 
-	err = compress(inputFilePath, outputFilePath, subPath)
+	paths := []string{inputFilePath}
+	for _, include := range opts.Includes {
+		includePath, absErr := filepath.Abs(include)
+		if absErr != nil {
+			return absErr
+		}
+
+		if filepath.Dir(includePath) != subPath {
+			return fmt.Errorf("targz: include %q is not a sibling of %q", include, inputFilePath)
+		}
+
+		paths = append(paths, includePath)
+	}
+
+	err = compress(paths, outputFilePath, subPath, opts)
 	if err != nil {
 		return err
 	}
@@ -67,7 +90,16 @@ func Compress(inputFilePath, outputFilePath string) (err error) {
 // Extract extracts an archive from the file inputFilePath points to in the directory outputFilePath points to.
 // It tries to create the directory structure outputFilePath contains if it doesn't exist.
 // It returns potential errors to be checked or nil if everything works.
-func Extract(inputFilePath, outputFilePath string) (err error) {
+//
+// Entries that escape outputFilePath, or that have an absolute Name or
+// Linkname, are rejected. Use ExtractWithOptions to opt out.
+func Extract(inputFilePath, outputFilePath string) error {
+	return ExtractWithOptions(inputFilePath, outputFilePath, Options{})
+}
+
+// ExtractWithOptions behaves like Extract, with opts controlling whether
+// absolute paths and archive entries that escape outputFilePath are allowed.
+func ExtractWithOptions(inputFilePath, outputFilePath string, opts Options) (err error) {
 	outputFilePath = stripTrailingSlashes(outputFilePath)
 	inputFilePath, outputFilePath, err = makeAbsolute(inputFilePath, outputFilePath)
 	if err != nil {
@@ -83,7 +115,7 @@ func Extract(inputFilePath, outputFilePath string) (err error) {
 		}
 	}()
 
-	return extract(inputFilePath, outputFilePath)
+	return extract(inputFilePath, outputFilePath, opts)
 }
 
 // Creates all directories with os.MkdirAll and returns a function to remove the first created directory so cleanup is possible.
@@ -150,11 +182,12 @@ func makeAbsolute(inputFilePath, outputFilePath string) (string, string, error)
 	return inputFilePath, outputFilePath, err
 }
 
-// The main interaction with tar and gzip. Creates an archive and recursively adds all files in the directory.
-// The finished archive contains just the directory added, not any parents.
-// This is possible by giving the whole path except the final directory in subPath.
-func compress(inPath, outFilePath, subPath string) (err error) {
-	files, err := os.ReadDir(inPath)
+// The main interaction with tar and gzip. Creates an archive and recursively adds all files in
+// each of paths (inPath and, if set, opts.Includes). The finished archive contains just the
+// directories added, not any of their parents, by giving the whole path except the final
+// directory in subPath.
+func compress(paths []string, outFilePath, subPath string, opts Options) (err error) {
+	files, err := os.ReadDir(paths[0])
 	if err != nil {
 		return err
 	}
@@ -173,124 +206,36 @@ func compress(inPath, outFilePath, subPath string) (err error) {
 		}
 	}()
 
-	gzipWriter := gzip.NewWriter(file)
-	tarWriter := tar.NewWriter(gzipWriter)
-
-	err = writeDirectory(inPath, tarWriter, subPath)
-	if err != nil {
-		return err
-	}
-
-	err = tarWriter.Close()
-	if err != nil {
-		return err
-	}
-
-	err = gzipWriter.Close()
-	if err != nil {
-		return err
-	}
-
-	err = file.Close()
+	tarWriter, err := NewWriter(file, Options{
+		Compression: compressionByExtension(outFilePath),
+		Concurrency: opts.Concurrency,
+		BlockSize:   opts.BlockSize,
+	})
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-// Read a directory and write it to the tar writer. Recursive function that writes all sub folders.
-func writeDirectory(directory string, tarWriter *tar.Writer, subPath string) error {
-	// Handle wildcards
-	if strings.Contains(directory, "*") {
-		matches, err := filepath.Glob(directory)
-		if err != nil {
+	for _, inPath := range paths {
+		if err = writeDirectory(inPath, tarWriter.Writer, subPath, opts); err != nil {
 			return err
 		}
-
-		for _, match := range matches {
-			if err := writeDirectory(match, tarWriter, subPath); err != nil {
-				return err
-			}
-		}
-
-		return nil
-	}
-
-	files, err := os.ReadDir(directory)
-	if err != nil {
-		return err
-	}
-
-	for _, dirEntry := range files {
-		currentPath := filepath.Join(directory, dirEntry.Name())
-		if dirEntry.IsDir() {
-			err := writeDirectory(currentPath, tarWriter, subPath)
-			if err != nil {
-				return err
-			}
-		} else {
-			fileInfo, err := dirEntry.Info()
-			if err != nil {
-				return err
-			}
-
-			err = writeTarGz(currentPath, tarWriter, fileInfo, subPath)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
-
-// Write path without the prefix in subPath to tar writer.
-func writeTarGz(path string, tarWriter *tar.Writer, fileInfo os.FileInfo, subPath string) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		handleErrorInDefer(file.Close())
-	}()
-
-	evaluatedPath, err := filepath.EvalSymlinks(path)
-	if err != nil {
-		return err
 	}
 
-	subPath, err = filepath.EvalSymlinks(subPath)
-	if err != nil {
-		return err
-	}
-
-	link := ""
-	if evaluatedPath != path {
-		link = evaluatedPath
-	}
-
-	header, err := tar.FileInfoHeader(fileInfo, link)
-	if err != nil {
-		return err
-	}
-	header.Name = evaluatedPath[len(subPath):]
-
-	err = tarWriter.WriteHeader(header)
+	err = tarWriter.Close()
 	if err != nil {
 		return err
 	}
 
-	_, err = io.Copy(tarWriter, file)
+	err = file.Close()
 	if err != nil {
 		return err
 	}
 
-	return err
+	return nil
 }
 
 // Extract the file in filePath to directory.
-func extract(filePath string, directory string) error {
+func extract(filePath string, directory string, opts Options) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
@@ -299,16 +244,14 @@ func extract(filePath string, directory string) error {
 		handleErrorInDefer(file.Close())
 	}()
 
-	gzipReader, err := gzip.NewReader(bufio.NewReader(file))
+	tarReader, err := NewReader(file, opts)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		handleErrorInDefer(gzipReader.Close())
+		handleErrorInDefer(tarReader.Close())
 	}()
 
-	tarReader := tar.NewReader(gzipReader)
-
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
@@ -318,50 +261,65 @@ func extract(filePath string, directory string) error {
 			return err
 		}
 
-		fileInfo := header.FileInfo()
-		dir := filepath.Join(directory, filepath.Dir(header.Name))
-		filename := filepath.Join(dir, fileInfo.Name())
-
-		err = os.MkdirAll(dir, 0755)
+		filename, err := validateExtractPath(directory, header.Name, opts.AllowAbsolutePaths, opts.AllowSymlinkEscape)
 		if err != nil {
 			return err
 		}
 
-		file, err := os.Create(filename)
-		if err != nil {
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
 			return err
 		}
 
-		writer := bufio.NewWriter(file)
+		if err := extractEntry(tarReader, header, directory, filename, opts); err != nil {
+			return err
+		}
+	}
 
-		buffer := make([]byte, 4096)
-		for {
-			n, err := tarReader.Read(buffer)
-			if err != nil && err != io.EOF {
-				panic(err)
-			}
-			if n == 0 {
-				break
-			}
+	return nil
+}
 
-			_, err = writer.Write(buffer[:n])
-			if err != nil {
-				return err
-			}
+// extractEntry creates the file, directory, link, or device described by
+// header at filename, dispatching on header.Typeflag, then restores the
+// metadata (mode, mtime, and optionally ownership) it carries.
+func extractEntry(tarReader *Reader, header *tar.Header, directory, filename string, opts Options) error {
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(filename, header.FileInfo().Mode()); err != nil {
+			return err
 		}
-
-		err = writer.Flush()
-		if err != nil {
+	case tar.TypeSymlink:
+		if err := extractSymlink(directory, filename, header, opts); err != nil {
 			return err
 		}
-
-		err = file.Close()
-		if err != nil {
+		return restoreMetadata(filename, header, opts)
+	case tar.TypeLink:
+		if err := extractHardlink(directory, filename, header, opts); err != nil {
+			return err
+		}
+	case tar.TypeChar, tar.TypeBlock:
+		if err := extractDevice(filename, header, opts); err != nil {
+			return err
+		}
+	default:
+		if err := extractRegularFile(tarReader, filename, header); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return restoreMetadata(filename, header, opts)
+}
+
+// exists reports whether path exists on disk.
+func exists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return false, err
 }
 
 func handleErrorInDefer(err error) {
@@ -0,0 +1,156 @@
+package targz
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hardlinkKey identifies a file by device and inode, used to detect that two
+// paths walked during compress are hardlinks to the same underlying file.
+type hardlinkKey struct {
+	dev uint64
+	ino uint64
+}
+
+// Read a directory and write it to the tar writer. Walks the whole tree
+// rooted at directory, emitting a tar.Header for every entry it finds:
+// regular files, directories, symlinks (preserved as links, not followed),
+// hardlinks (written once in full and again as TypeLink), and device files.
+// Entries matching opts.ExcludePatterns, or that opts.Filter skips, are left
+// out of the archive.
+func writeDirectory(directory string, tarWriter *tar.Writer, subPath string, opts Options) error {
+	// Handle wildcards
+	if strings.Contains(directory, "*") {
+		matches, err := filepath.Glob(directory)
+		if err != nil {
+			return err
+		}
+
+		for _, match := range matches {
+			if err := writeDirectory(match, tarWriter, subPath, opts); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	evaluatedDirectory, err := filepath.EvalSymlinks(directory)
+	if err != nil {
+		return err
+	}
+
+	evaluatedSubPath, err := filepath.EvalSymlinks(subPath)
+	if err != nil {
+		return err
+	}
+
+	hardlinks := map[hardlinkKey]string{}
+
+	return filepath.Walk(evaluatedDirectory, func(currentPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		return writeEntry(tarWriter, currentPath, evaluatedSubPath, info, hardlinks, opts)
+	})
+}
+
+// writeEntry writes the tar.Header (and, for regular files, the contents)
+// describing currentPath to tarWriter. hardlinks tracks inodes of files
+// already written (not merely seen) during this walk, so later paths
+// sharing one are written as TypeLink instead; on platforms without
+// inode/device information (e.g. Windows) it has no effect and every
+// regular file is written in full.
+// It returns filepath.SkipDir for a directory excluded by opts, so its
+// contents are left out of the archive too.
+func writeEntry(tarWriter *tar.Writer, currentPath, subPath string, lstat os.FileInfo, hardlinks map[hardlinkKey]string, opts Options) error {
+	name := strings.TrimPrefix(currentPath[len(subPath):], string(filepath.Separator))
+
+	excluded, err := matchesAny(opts.ExcludePatterns, name)
+	if err != nil {
+		return err
+	}
+
+	var link string
+	if lstat.Mode()&os.ModeSymlink != 0 {
+		link, err = os.Readlink(currentPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(lstat, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	applyStatMetadata(lstat, header, hardlinks)
+
+	skip := excluded
+	if !skip && opts.Filter != nil {
+		skip, err = opts.Filter(header, currentPath)
+		if err != nil {
+			return err
+		}
+	}
+	if skip {
+		if lstat.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+
+	// Only now that the entry is confirmed to be written can its inode be
+	// recorded as a hardlink target - an excluded/filtered-out copy must
+	// not become a later entry's (unwritten) Linkname.
+	recordHardlink(lstat, header.Name, hardlinks)
+
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if header.Typeflag != tar.TypeReg {
+		return nil
+	}
+
+	file, err := os.Open(currentPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		handleErrorInDefer(file.Close())
+	}()
+
+	_, err = io.Copy(tarWriter, file)
+	return err
+}
+
+// matchesAny reports whether name matches any of patterns. A pattern ending
+// in "/**" matches name itself or anything below it; other patterns are
+// matched with filepath.Match.
+func matchesAny(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/**") {
+			rest := strings.TrimSuffix(pattern, "/**")
+			if name == rest || strings.HasPrefix(name, rest+"/") {
+				return true, nil
+			}
+			continue
+		}
+
+		matched, err := filepath.Match(pattern, name)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,46 @@
+//go:build !windows
+
+package targz
+
+import (
+	"archive/tar"
+	"os"
+	"syscall"
+)
+
+// applyStatMetadata inspects lstat's underlying syscall.Stat_t and mutates
+// header in place: a regular file whose device/inode was already recorded in
+// hardlinks (by a previous, actually-written copy) becomes a TypeLink
+// pointing at it. Device files need no help here - tar.FileInfoHeader has
+// already set their Typeflag and computed Devmajor/Devminor using the
+// correct major/minor encoding for the current GOOS.
+func applyStatMetadata(lstat os.FileInfo, header *tar.Header, hardlinks map[hardlinkKey]string) {
+	stat, ok := lstat.Sys().(*syscall.Stat_t)
+	if !ok || !lstat.Mode().IsRegular() || stat.Nlink <= 1 {
+		return
+	}
+
+	key := hardlinkKey{dev: uint64(stat.Dev), ino: stat.Ino}
+	if original, seen := hardlinks[key]; seen {
+		header.Typeflag = tar.TypeLink
+		header.Linkname = original
+		header.Size = 0
+	}
+}
+
+// recordHardlink registers name as the archived copy of the hardlinked file
+// lstat describes, so that later paths sharing its device/inode are written
+// as a TypeLink referencing it instead of being written out in full. It's a
+// no-op for anything that isn't a multiply-linked regular file, or whose
+// inode is already recorded.
+func recordHardlink(lstat os.FileInfo, name string, hardlinks map[hardlinkKey]string) {
+	stat, ok := lstat.Sys().(*syscall.Stat_t)
+	if !ok || !lstat.Mode().IsRegular() || stat.Nlink <= 1 {
+		return
+	}
+
+	key := hardlinkKey{dev: uint64(stat.Dev), ino: stat.Ino}
+	if _, seen := hardlinks[key]; !seen {
+		hardlinks[key] = name
+	}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package targz
+
+import (
+	"archive/tar"
+	"os"
+)
+
+// applyStatMetadata is a no-op on Windows: os.FileInfo.Sys() doesn't expose
+// inode/device numbers there, so hardlinks are archived as independent
+// regular files (each written in full) and device files aren't detected.
+func applyStatMetadata(lstat os.FileInfo, header *tar.Header, hardlinks map[hardlinkKey]string) {}
+
+// recordHardlink is a no-op on Windows; see applyStatMetadata.
+func recordHardlink(lstat os.FileInfo, name string, hardlinks map[hardlinkKey]string) {}
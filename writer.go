@@ -0,0 +1,57 @@
+package targz
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Writer tars and compresses data written to it, one tar.Writer method call
+// at a time. It wraps an underlying io.Writer and must be closed to flush
+// both the tar trailer and the compression codec.
+type Writer struct {
+	*tar.Writer
+	compressor io.WriteCloser
+}
+
+// NewWriter returns a Writer that tars and compresses onto w using the codec
+// selected by opts.Compression. Callers must call Close when done writing.
+func NewWriter(w io.Writer, opts Options) (*Writer, error) {
+	compressor, err := newCompressor(w, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{Writer: tar.NewWriter(compressor), compressor: compressor}, nil
+}
+
+// Close flushes the tar trailer and then the compression codec, returning the
+// first error encountered.
+func (w *Writer) Close() error {
+	if err := w.Writer.Close(); err != nil {
+		return err
+	}
+
+	return w.compressor.Close()
+}
+
+func newCompressor(w io.Writer, opts Options) (io.WriteCloser, error) {
+	switch opts.Compression {
+	case Uncompressed:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		if opts.Concurrency > 0 {
+			return newParallelGzipWriter(w, opts.Concurrency, opts.BlockSize), nil
+		}
+		return gzip.NewWriter(w), nil
+	case Bzip2:
+		return nil, fmt.Errorf("targz: writing bzip2 archives is not supported")
+	default:
+		return nil, fmt.Errorf("targz: unknown compression %d", opts.Compression)
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,75 @@
+package targz
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func Test_WriterReaderRoundTrip(t *testing.T) {
+	for _, compression := range []Compression{Uncompressed, Gzip} {
+		var buf bytes.Buffer
+
+		writer, err := NewWriter(&buf, Options{Compression: compression})
+		if err != nil {
+			t.Fatalf("NewWriter error: %s", err)
+		}
+
+		content := []byte("hello targz")
+		err = writer.WriteHeader(&tar.Header{Name: "hello.txt", Size: int64(len(content)), Mode: 0644})
+		if err != nil {
+			t.Fatalf("WriteHeader error: %s", err)
+		}
+		if _, err := writer.Write(content); err != nil {
+			t.Fatalf("Write error: %s", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Close error: %s", err)
+		}
+
+		reader, err := NewReader(&buf, Options{})
+		if err != nil {
+			t.Fatalf("NewReader error: %s", err)
+		}
+
+		header, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Next error: %s", err)
+		}
+		if header.Name != "hello.txt" {
+			t.Errorf("expected header name %q, got %q", "hello.txt", header.Name)
+		}
+
+		got, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("ReadAll error: %s", err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("expected content %q, got %q", content, got)
+		}
+
+		if err := reader.Close(); err != nil {
+			t.Fatalf("Close error: %s", err)
+		}
+	}
+}
+
+func Test_DetectCompression(t *testing.T) {
+	cases := []struct {
+		name string
+		buf  []byte
+		want Compression
+	}{
+		{"gzip", []byte{0x1F, 0x8B, 0x08}, Gzip},
+		{"bzip2", []byte{0x42, 0x5A, 0x68, 0x39}, Bzip2},
+		{"none", []byte("not an archive"), Uncompressed},
+		{"empty", nil, Uncompressed},
+	}
+
+	for _, c := range cases {
+		if got := DetectCompression(c.buf); got != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, got)
+		}
+	}
+}